@@ -11,6 +11,16 @@ import (
 // The VirtioDevice interface is an interface which is implemented by all virtio devices.
 type VirtioDevice VMComponent
 
+// Save/restore (snapshot) support: the control-plane endpoint that pauses a
+// running VM, calls VZVirtualMachine.saveMachineStateToURL:, and the
+// matching --restore-from boot path that calls
+// restoreMachineStateFromURL:, live in vfkit's REST control layer and
+// startup path, not in this package. Restore re-attaches a device's backing
+// by its stable ID field (see VirtioBlk, USBMassStorage and VirtioNet)
+// rather than by command-line order. Only raw disks and bridged/NAT
+// networks are snapshotable this way; vsock and virtio-fs devices must be
+// quiesced or rejected before a snapshot is taken.
+
 // VirtioVsock configures of a virtio-vsock device allowing 2-way communication
 // between the host and the virtual machine type
 type VirtioVsock struct {
@@ -36,12 +46,47 @@ type VirtioFs struct {
 	MountTag  string
 }
 
+// VirtioGPU configures a virtio-gpu graphics device, backed by
+// VZVirtioGraphicsDeviceConfiguration with one or more
+// VZMacGraphicsDisplayConfiguration scanouts (see Scanouts).
+type VirtioGPU struct {
+	Width  uint32
+	Height uint32
+	// Scanouts is the number of display scanouts to expose. If zero, a
+	// single scanout is used.
+	Scanouts uint32
+}
+
+// VirtioInputType identifies the kind of USB HID device a VirtioInput
+// device presents to the guest.
+type VirtioInputType string
+
+const (
+	VirtioInputKeyboard VirtioInputType = "keyboard"
+	VirtioInputPointing VirtioInputType = "pointing"
+)
+
+// VirtioInput configures a USB input device (keyboard or pointing device),
+// backed by VZUSBKeyboardConfiguration or
+// VZUSBScreenCoordinatePointingDeviceConfiguration.
+type VirtioInput struct {
+	InputType VirtioInputType
+}
+
 // virtioRng configures a random number generator (RNG) device.
 type VirtioRng struct {
 }
 
-// TODO: Add BridgedNetwork support
-// https://github.com/Code-Hex/vz/blob/d70a0533bf8ed0fa9ab22fa4d4ca554b7c3f3ce5/network.go#L81-L82
+// VirtioSnd configures a virtio-sound (virtio-snd) device, exposing an
+// ALSA/virtio-sound card to the guest backed by the host's audio engine.
+type VirtioSnd struct {
+	// Input enables a host audio input stream for the device.
+	Input bool
+	// Output enables a host audio output stream for the device.
+	Output bool
+	// Name is an optional label for the sound device.
+	Name string
+}
 
 // VirtioNet configures the virtual machine networking.
 type VirtioNet struct {
@@ -50,15 +95,61 @@ type VirtioNet struct {
 	// file parameter is holding a connected datagram socket.
 	// see https://github.com/Code-Hex/vz/blob/7f648b6fb9205d6f11792263d79876e3042c33ec/network.go#L113-L155
 	Socket *os.File
-}
-
-// VirtioSerial configures the virtual machine serial ports.
+	// Bridge is the name of a host network interface (e.g. "en0") this
+	// device should be bridged to.
+	// see https://github.com/Code-Hex/vz/blob/d70a0533bf8ed0fa9ab22fa4d4ca554b7c3f3ce5/network.go#L81-L82
+	Bridge string
+	// ID is a stable identifier for this device, used to re-attach the same
+	// backing by identity (rather than command-line order) when restoring a
+	// VM from a snapshot.
+	ID string
+}
+
+// VirtioSerial configures the virtual machine serial ports. Exactly one of
+// LogFile, PTY, Stdio, TCP or Unix must be set.
 type VirtioSerial struct {
+	// LogFile is the path to a file the guest's serial output is appended
+	// to. This is a one-way (guest to host) endpoint.
 	LogFile string
+	// PTY, when true, allocates a host pseudo-terminal for the serial port;
+	// the slave path is printed to vfkit's output at startup.
+	PTY bool
+	// Stdio, when true, attaches the serial port to vfkit's own stdin and
+	// stdout.
+	Stdio bool
+	// TCP, when set, attaches the serial port to a TCP endpoint.
+	TCP *VirtioSerialTCP
+	// Unix, when set, attaches the serial port to a unix socket endpoint.
+	Unix *VirtioSerialUnix
+}
+
+// VirtioSerialTCP configures a TCP serial port endpoint.
+type VirtioSerialTCP struct {
+	Host string
+	Port uint16
+	// If true, the host will listen for a connection on Host:Port. If
+	// false, the host will connect to Host:Port.
+	Listen bool
+}
+
+// VirtioSerialUnix configures a unix socket serial port endpoint.
+type VirtioSerialUnix struct {
+	Path string
+	// If true, the host will listen for a connection on Path. If false, the
+	// host will connect to Path.
+	Listen bool
 }
 
-// TODO: Add VirtioBalloon
-// https://github.com/Code-Hex/vz/blob/master/memory_balloon.go
+// VirtioBalloon configures a virtio-balloon device, allowing the host to
+// reclaim or grant guest memory while the virtual machine is running.
+type VirtioBalloon struct {
+	// Target is the memory size, in MiB, the balloon should inflate or
+	// deflate the guest towards.
+	Target uint64
+	// DeflateOnOOM, when true, automatically deflates the balloon when the
+	// guest is under memory pressure.
+	DeflateOnOOM bool
+}
 
 type option struct {
 	key   string
@@ -97,16 +188,24 @@ func deviceFromCmdLine(deviceOpts string) (VirtioDevice, error) {
 	}
 	var dev VirtioDevice
 	switch opts[0] {
+	case "virtio-balloon":
+		dev = &VirtioBalloon{}
 	case "virtio-blk":
 		dev = virtioBlkNewEmpty()
 	case "virtio-fs":
 		dev = &VirtioFs{}
+	case "virtio-gpu":
+		dev = &VirtioGPU{}
+	case "virtio-input":
+		dev = &VirtioInput{}
 	case "virtio-net":
 		dev = &VirtioNet{}
 	case "virtio-rng":
 		dev = &VirtioRng{}
 	case "virtio-serial":
 		dev = &VirtioSerial{}
+	case "virtio-snd":
+		dev = &VirtioSnd{}
 	case "virtio-vsock":
 		dev = &VirtioVsock{}
 	case "usb-mass-storage":
@@ -132,23 +231,155 @@ func VirtioSerialNew(logFilePath string) (VirtioDevice, error) {
 	}, nil
 }
 
+// VirtioSerialNewPTY creates a new serial device backed by a host
+// pseudo-terminal. The slave path is printed to vfkit's output at startup.
+func VirtioSerialNewPTY() (VirtioDevice, error) {
+	return &VirtioSerial{
+		PTY: true,
+	}, nil
+}
+
+// VirtioSerialNewStdio creates a new serial device attached to vfkit's own
+// stdin and stdout.
+func VirtioSerialNewStdio() (VirtioDevice, error) {
+	return &VirtioSerial{
+		Stdio: true,
+	}, nil
+}
+
+// VirtioSerialNewTCP creates a new serial device attached to a TCP endpoint.
+// When listen is true, the host listens for a connection on host:port; when
+// false, the host connects to host:port.
+func VirtioSerialNewTCP(host string, port uint16, listen bool) (VirtioDevice, error) {
+	return &VirtioSerial{
+		TCP: &VirtioSerialTCP{Host: host, Port: port, Listen: listen},
+	}, nil
+}
+
+// VirtioSerialNewUnix creates a new serial device attached to a unix socket
+// endpoint. When listen is true, the host listens for a connection on path;
+// when false, the host connects to path.
+func VirtioSerialNewUnix(path string, listen bool) (VirtioDevice, error) {
+	return &VirtioSerial{
+		Unix: &VirtioSerialUnix{Path: path, Listen: listen},
+	}, nil
+}
+
+func (dev *VirtioSerial) validate() error {
+	endpointsSet := 0
+	for _, isSet := range []bool{dev.LogFile != "", dev.PTY, dev.Stdio, dev.TCP != nil, dev.Unix != nil} {
+		if isSet {
+			endpointsSet++
+		}
+	}
+	if endpointsSet != 1 {
+		return fmt.Errorf("virtio-serial needs exactly one of 'logFilePath', 'pty', 'stdio', 'tcp' or 'unix' to be set")
+	}
+
+	return nil
+}
+
+// TODO: plumb the resulting io.ReadWriter (or its underlying file
+// descriptor) into VZVirtioConsoleDeviceSerialPortConfiguration.
+// https://github.com/Code-Hex/vz/blob/master/serialport.go
 func (dev *VirtioSerial) ToCmdLine() ([]string, error) {
-	if dev.LogFile == "" {
-		return nil, fmt.Errorf("virtio-serial needs the path to the log file")
+	if err := dev.validate(); err != nil {
+		return nil, err
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString("virtio-serial")
+	switch {
+	case dev.LogFile != "":
+		fmt.Fprintf(&builder, ",logFilePath=%s", dev.LogFile)
+	case dev.PTY:
+		builder.WriteString(",pty")
+	case dev.Stdio:
+		builder.WriteString(",stdio")
+	case dev.TCP != nil:
+		fmt.Fprintf(&builder, ",tcp=%s:%d,mode=%s", dev.TCP.Host, dev.TCP.Port, serialEndpointModeStr(dev.TCP.Listen))
+	case dev.Unix != nil:
+		fmt.Fprintf(&builder, ",unix=%s,mode=%s", dev.Unix.Path, serialEndpointModeStr(dev.Unix.Listen))
+	}
+
+	return []string{"--device", builder.String()}, nil
+}
+
+func serialEndpointModeStr(listen bool) string {
+	if listen {
+		return "listen"
+	}
+	return "connect"
+}
+
+func serialEndpointModeFromStr(mode string) (bool, error) {
+	switch mode {
+	case "listen":
+		return true, nil
+	case "connect":
+		return false, nil
+	default:
+		return false, fmt.Errorf("Unknown mode for virtio-serial devices: %s", mode)
 	}
-	return []string{"--device", fmt.Sprintf("virtio-serial,logFilePath=%s", dev.LogFile)}, nil
 }
 
 func (dev *VirtioSerial) FromOptions(options []option) error {
+	// mode applies to whichever of 'tcp'/'unix' is set, and is resolved
+	// after the loop below so it can be given in any order relative to
+	// them (e.g. "mode=listen,tcp=...").
+	var mode string
+	modeSet := false
+
 	for _, option := range options {
 		switch option.key {
 		case "logFilePath":
 			dev.LogFile = option.value
+		case "pty":
+			if option.value != "" {
+				return fmt.Errorf("Unexpected value for virtio-serial 'pty' option: %s", option.value)
+			}
+			dev.PTY = true
+		case "stdio":
+			if option.value != "" {
+				return fmt.Errorf("Unexpected value for virtio-serial 'stdio' option: %s", option.value)
+			}
+			dev.Stdio = true
+		case "tcp":
+			host, portStr, err := net.SplitHostPort(option.value)
+			if err != nil {
+				return err
+			}
+			port, err := strconv.ParseUint(portStr, 10, 16)
+			if err != nil {
+				return err
+			}
+			dev.TCP = &VirtioSerialTCP{Host: host, Port: uint16(port), Listen: true}
+		case "unix":
+			dev.Unix = &VirtioSerialUnix{Path: option.value, Listen: true}
+		case "mode":
+			mode = option.value
+			modeSet = true
 		default:
 			return fmt.Errorf("Unknown option for virtio-serial devices: %s", option.key)
 		}
 	}
-	return nil
+
+	if modeSet {
+		listen, err := serialEndpointModeFromStr(mode)
+		if err != nil {
+			return err
+		}
+		switch {
+		case dev.TCP != nil:
+			dev.TCP.Listen = listen
+		case dev.Unix != nil:
+			dev.Unix.Listen = listen
+		default:
+			return fmt.Errorf("virtio-serial 'mode' option requires 'tcp' or 'unix' to be set")
+		}
+	}
+
+	return dev.validate()
 }
 
 // VirtioNetNew creates a new network device for the virtual machine. It will
@@ -179,12 +410,29 @@ func (dev *VirtioNet) SetSocket(file *os.File) {
 	dev.Nat = false
 }
 
+// SetBridge binds the network interface to the host bridge interface named
+// bridgeIfName (e.g. "en0"), instead of using NAT or a datagram socket.
+func (dev *VirtioNet) SetBridge(bridgeIfName string) {
+	dev.Bridge = bridgeIfName
+	dev.Nat = false
+}
+
 func (dev *VirtioNet) validate() error {
-	if dev.Nat && dev.Socket != nil {
-		return fmt.Errorf("'nat' and 'fd' cannot be set at the same time")
+	numBackends := 0
+	if dev.Nat {
+		numBackends++
+	}
+	if dev.Socket != nil {
+		numBackends++
+	}
+	if dev.Bridge != "" {
+		numBackends++
 	}
-	if !dev.Nat && dev.Socket == nil {
-		return fmt.Errorf("One of 'nat' or 'fd' must be set")
+	if numBackends == 0 {
+		return fmt.Errorf("One of 'nat', 'fd' or 'bridge' must be set")
+	}
+	if numBackends > 1 {
+		return fmt.Errorf("Only one of 'nat', 'fd' or 'bridge' can be set at the same time")
 	}
 
 	return nil
@@ -197,9 +445,12 @@ func (dev *VirtioNet) ToCmdLine() ([]string, error) {
 
 	builder := strings.Builder{}
 	builder.WriteString("virtio-net")
-	if dev.Nat {
+	switch {
+	case dev.Nat:
 		builder.WriteString(",nat")
-	} else {
+	case dev.Bridge != "":
+		fmt.Fprintf(&builder, ",bridge=%s", dev.Bridge)
+	default:
 		fmt.Fprintf(&builder, ",fd=%d", dev.Socket.Fd())
 	}
 
@@ -207,6 +458,10 @@ func (dev *VirtioNet) ToCmdLine() ([]string, error) {
 		builder.WriteString(fmt.Sprintf(",mac=%s", dev.MacAddress))
 	}
 
+	if dev.ID != "" {
+		fmt.Fprintf(&builder, ",id=%s", dev.ID)
+	}
+
 	return []string{"--device", builder.String()}, nil
 }
 
@@ -230,6 +485,10 @@ func (dev *VirtioNet) FromOptions(options []option) error {
 				return err
 			}
 			dev.Socket = os.NewFile(uintptr(fd), "vfkit virtio-net socket")
+		case "bridge":
+			dev.Bridge = option.value
+		case "id":
+			dev.ID = option.value
 		default:
 			return fmt.Errorf("Unknown option for virtio-net devices: %s", option.key)
 		}
@@ -255,6 +514,114 @@ func (dev *VirtioRng) FromOptions(options []option) error {
 	return nil
 }
 
+// VirtioSndNew creates a new virtio-sound device for the virtual machine.
+// At least one of input or output must be true.
+func VirtioSndNew(input, output bool) (VirtioDevice, error) {
+	if !input && !output {
+		return nil, fmt.Errorf("virtio-snd needs at least one of 'input' or 'output' to be set")
+	}
+	return &VirtioSnd{
+		Input:  input,
+		Output: output,
+	}, nil
+}
+
+func (dev *VirtioSnd) ToCmdLine() ([]string, error) {
+	if !dev.Input && !dev.Output {
+		return nil, fmt.Errorf("virtio-snd needs at least one of 'input' or 'output' to be set")
+	}
+	builder := strings.Builder{}
+	builder.WriteString("virtio-snd")
+	if dev.Input {
+		builder.WriteString(",input=true")
+	}
+	if dev.Output {
+		builder.WriteString(",output=true")
+	}
+	if dev.Name != "" {
+		fmt.Fprintf(&builder, ",name=%s", dev.Name)
+	}
+
+	return []string{"--device", builder.String()}, nil
+}
+
+func (dev *VirtioSnd) FromOptions(options []option) error {
+	for _, option := range options {
+		switch option.key {
+		case "input":
+			input, err := strconv.ParseBool(option.value)
+			if err != nil {
+				return err
+			}
+			dev.Input = input
+		case "output":
+			output, err := strconv.ParseBool(option.value)
+			if err != nil {
+				return err
+			}
+			dev.Output = output
+		case "name":
+			dev.Name = option.value
+		default:
+			return fmt.Errorf("Unknown option for virtio-snd devices: %s", option.key)
+		}
+	}
+
+	if !dev.Input && !dev.Output {
+		return fmt.Errorf("virtio-snd needs at least one of 'input' or 'output' to be set")
+	}
+
+	return nil
+}
+
+// VirtioBalloonNew creates a new virtio-balloon device, initially targeting
+// target MiB of guest memory.
+func VirtioBalloonNew(target uint64, deflateOnOOM bool) (VirtioDevice, error) {
+	return &VirtioBalloon{
+		Target:       target,
+		DeflateOnOOM: deflateOnOOM,
+	}, nil
+}
+
+// TODO: expose a runtime control endpoint (REST/vsock) that lets the host
+// resize a running VM's balloon target after startup, similar to how
+// cloud-hypervisor and crosvm let the host shrink/grow guest memory at
+// runtime. That control-plane lives in vfkit's REST layer, not in this
+// package.
+func (dev *VirtioBalloon) ToCmdLine() ([]string, error) {
+	builder := strings.Builder{}
+	builder.WriteString("virtio-balloon")
+	fmt.Fprintf(&builder, ",target=%d", dev.Target)
+	if dev.DeflateOnOOM {
+		builder.WriteString(",deflateOnOOM=true")
+	}
+
+	return []string{"--device", builder.String()}, nil
+}
+
+func (dev *VirtioBalloon) FromOptions(options []option) error {
+	for _, option := range options {
+		switch option.key {
+		case "target":
+			target, err := strconv.ParseUint(option.value, 10, 64)
+			if err != nil {
+				return err
+			}
+			dev.Target = target
+		case "deflateOnOOM":
+			deflateOnOOM, err := strconv.ParseBool(option.value)
+			if err != nil {
+				return err
+			}
+			dev.DeflateOnOOM = deflateOnOOM
+		default:
+			return fmt.Errorf("Unknown option for virtio-balloon devices: %s", option.key)
+		}
+	}
+
+	return nil
+}
+
 func virtioBlkNewEmpty() *VirtioBlk {
 	return &VirtioBlk{
 		StorageConfig: StorageConfig{
@@ -415,18 +782,59 @@ func USBMassStorageNew(imagePath string) (VMComponent, error) {
 	return usbMassStorage, nil
 }
 
+// DiskCachingMode controls how a disk image is cached by the host, mirroring
+// VZDiskImageCachingMode.
+type DiskCachingMode string
+
+const (
+	CachingCached   DiskCachingMode = "cached"
+	CachingUncached DiskCachingMode = "uncached"
+)
+
+// DiskSynchronizationMode controls how a disk image is synchronized to the
+// host, mirroring VZDiskImageSynchronizationMode.
+type DiskSynchronizationMode string
+
+const (
+	SynchronizationNone DiskSynchronizationMode = "none"
+	SynchronizationFull DiskSynchronizationMode = "full"
+)
+
 // StorageConfig configures a disk device.
 type StorageConfig struct {
 	DevName   string
 	ImagePath string
 	ReadOnly  bool
+	// ID is a stable identifier for this device, used to re-attach the same
+	// backing image by identity (rather than command-line order) when
+	// restoring a VM from a snapshot.
+	ID string
+	// Cache controls the disk image's host-side caching mode. If unset, the
+	// Virtualization.framework default is used.
+	Cache DiskCachingMode
+	// Sync controls the disk image's host-side synchronization mode. If
+	// unset, the Virtualization.framework default is used.
+	Sync DiskSynchronizationMode
 }
 
 func (config *StorageConfig) ToCmdLine() ([]string, error) {
 	if config.ImagePath == "" {
 		return nil, fmt.Errorf("%s devices need the path to a disk image", config.DevName)
 	}
-	return []string{"--device", fmt.Sprintf("%s,path=%s", config.DevName, config.ImagePath)}, nil
+	cmdLine := fmt.Sprintf("%s,path=%s", config.DevName, config.ImagePath)
+	if config.ReadOnly {
+		cmdLine = fmt.Sprintf("%s,ro", cmdLine)
+	}
+	if config.Cache != "" {
+		cmdLine = fmt.Sprintf("%s,cache=%s", cmdLine, config.Cache)
+	}
+	if config.Sync != "" {
+		cmdLine = fmt.Sprintf("%s,sync=%s", cmdLine, config.Sync)
+	}
+	if config.ID != "" {
+		cmdLine = fmt.Sprintf("%s,id=%s", cmdLine, config.ID)
+	}
+	return []string{"--device", cmdLine}, nil
 }
 
 func (config *StorageConfig) FromOptions(options []option) error {
@@ -434,9 +842,139 @@ func (config *StorageConfig) FromOptions(options []option) error {
 		switch option.key {
 		case "path":
 			config.ImagePath = option.value
+		case "id":
+			config.ID = option.value
+		case "ro":
+			if option.value != "" {
+				return fmt.Errorf("Unexpected value for %s 'ro' option: %s", config.DevName, option.value)
+			}
+			config.ReadOnly = true
+		case "readOnly":
+			readOnly, err := strconv.ParseBool(option.value)
+			if err != nil {
+				return err
+			}
+			config.ReadOnly = readOnly
+		case "cache":
+			switch DiskCachingMode(option.value) {
+			case CachingCached, CachingUncached:
+				config.Cache = DiskCachingMode(option.value)
+			default:
+				return fmt.Errorf("Unknown cache mode for %s devices: %s", config.DevName, option.value)
+			}
+		case "sync":
+			switch DiskSynchronizationMode(option.value) {
+			case SynchronizationNone, SynchronizationFull:
+				config.Sync = DiskSynchronizationMode(option.value)
+			default:
+				return fmt.Errorf("Unknown sync mode for %s devices: %s", config.DevName, option.value)
+			}
 		default:
 			return fmt.Errorf("Unknown option for %s devices: %s", config.DevName, option.key)
 		}
 	}
 	return nil
 }
+
+// VirtioGPUNew creates a new virtio-gpu graphics device with the given
+// scanout resolution.
+func VirtioGPUNew(width, height uint32) (VirtioDevice, error) {
+	return &VirtioGPU{
+		Width:  width,
+		Height: height,
+	}, nil
+}
+
+func (dev *VirtioGPU) ToCmdLine() ([]string, error) {
+	if dev.Width == 0 || dev.Height == 0 {
+		return nil, fmt.Errorf("virtio-gpu needs a non-zero width and height")
+	}
+	cmdLine := fmt.Sprintf("virtio-gpu,width=%d,height=%d", dev.Width, dev.Height)
+	if dev.Scanouts != 0 {
+		cmdLine = fmt.Sprintf("%s,scanouts=%d", cmdLine, dev.Scanouts)
+	}
+	return []string{"--device", cmdLine}, nil
+}
+
+func (dev *VirtioGPU) FromOptions(options []option) error {
+	for _, option := range options {
+		switch option.key {
+		case "width":
+			width, err := strconv.ParseUint(option.value, 10, 32)
+			if err != nil {
+				return err
+			}
+			dev.Width = uint32(width)
+		case "height":
+			height, err := strconv.ParseUint(option.value, 10, 32)
+			if err != nil {
+				return err
+			}
+			dev.Height = uint32(height)
+		case "scanouts":
+			scanouts, err := strconv.ParseUint(option.value, 10, 32)
+			if err != nil {
+				return err
+			}
+			dev.Scanouts = uint32(scanouts)
+		default:
+			return fmt.Errorf("Unknown option for virtio-gpu devices: %s", option.key)
+		}
+	}
+
+	if dev.Width == 0 || dev.Height == 0 {
+		return fmt.Errorf("virtio-gpu needs a non-zero width and height")
+	}
+
+	return nil
+}
+
+// VirtioInputNew creates a new USB input device of the given type
+// ("keyboard" or "pointing").
+func VirtioInputNew(inputType VirtioInputType) (VirtioDevice, error) {
+	switch inputType {
+	case VirtioInputKeyboard, VirtioInputPointing:
+	default:
+		return nil, fmt.Errorf("unknown virtio-input type: %s", inputType)
+	}
+	return &VirtioInput{
+		InputType: inputType,
+	}, nil
+}
+
+func (dev *VirtioInput) ToCmdLine() ([]string, error) {
+	switch dev.InputType {
+	case VirtioInputKeyboard, VirtioInputPointing:
+	default:
+		return nil, fmt.Errorf("virtio-input needs a type of 'keyboard' or 'pointing'")
+	}
+	return []string{"--device", fmt.Sprintf("virtio-input,type=%s", dev.InputType)}, nil
+}
+
+func (dev *VirtioInput) FromOptions(options []option) error {
+	for _, option := range options {
+		switch option.key {
+		case "type":
+			switch VirtioInputType(option.value) {
+			case VirtioInputKeyboard, VirtioInputPointing:
+				dev.InputType = VirtioInputType(option.value)
+			default:
+				return fmt.Errorf("Unknown type for virtio-input devices: %s", option.value)
+			}
+		default:
+			return fmt.Errorf("Unknown option for virtio-input devices: %s", option.key)
+		}
+	}
+
+	switch dev.InputType {
+	case VirtioInputKeyboard, VirtioInputPointing:
+	default:
+		return fmt.Errorf("virtio-input needs a type of 'keyboard' or 'pointing'")
+	}
+
+	return nil
+}
+
+// TODO: the --gui flag that opens a Cocoa window attached to a VirtioGPU
+// device, and its event loop, belong to vfkit's main/startup packages, not
+// to this one.
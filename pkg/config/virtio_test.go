@@ -0,0 +1,259 @@
+package config
+
+import (
+	"testing"
+)
+
+// roundTrip renders dev to a command line and re-parses it, returning the
+// freshly parsed device.
+func roundTrip(t *testing.T, dev VirtioDevice) VirtioDevice {
+	t.Helper()
+
+	cmdLine, err := dev.ToCmdLine()
+	if err != nil {
+		t.Fatalf("ToCmdLine() returned an unexpected error: %v", err)
+	}
+	if len(cmdLine) != 2 {
+		t.Fatalf("ToCmdLine() returned %d arguments, expected 2", len(cmdLine))
+	}
+
+	parsed, err := deviceFromCmdLine(cmdLine[1])
+	if err != nil {
+		t.Fatalf("deviceFromCmdLine(%q) returned an unexpected error: %v", cmdLine[1], err)
+	}
+
+	return parsed
+}
+
+func TestVirtioSndRoundTrip(t *testing.T) {
+	dev, err := VirtioSndNew(true, true)
+	if err != nil {
+		t.Fatalf("VirtioSndNew() returned an unexpected error: %v", err)
+	}
+	dev.(*VirtioSnd).Name = "builtin"
+
+	parsed := roundTrip(t, dev).(*VirtioSnd)
+	if *parsed != *dev.(*VirtioSnd) {
+		t.Errorf("round-tripped device %+v does not match original %+v", parsed, dev)
+	}
+}
+
+func TestVirtioSndNewRejectsNoStreams(t *testing.T) {
+	if _, err := VirtioSndNew(false, false); err == nil {
+		t.Error("VirtioSndNew(false, false) should have returned an error")
+	}
+}
+
+func TestVirtioSndFromOptionsRejectsNoStreams(t *testing.T) {
+	for _, deviceOpts := range []string{
+		"virtio-snd",
+		"virtio-snd,input=false",
+		"virtio-snd,input=false,output=false",
+	} {
+		if _, err := deviceFromCmdLine(deviceOpts); err == nil {
+			t.Errorf("deviceFromCmdLine(%q) should have returned an error", deviceOpts)
+		}
+	}
+}
+
+func TestVirtioBalloonRoundTrip(t *testing.T) {
+	dev, err := VirtioBalloonNew(512, true)
+	if err != nil {
+		t.Fatalf("VirtioBalloonNew() returned an unexpected error: %v", err)
+	}
+
+	parsed := roundTrip(t, dev).(*VirtioBalloon)
+	if *parsed != *dev.(*VirtioBalloon) {
+		t.Errorf("round-tripped device %+v does not match original %+v", parsed, dev)
+	}
+}
+
+func TestVirtioBalloonFromOptionsRejectsBadValues(t *testing.T) {
+	for _, deviceOpts := range []string{
+		"virtio-balloon,target=notanumber",
+		"virtio-balloon,deflateOnOOM=notabool",
+	} {
+		if _, err := deviceFromCmdLine(deviceOpts); err == nil {
+			t.Errorf("deviceFromCmdLine(%q) should have returned an error", deviceOpts)
+		}
+	}
+}
+
+func TestVirtioNetBridgeRoundTrip(t *testing.T) {
+	dev := &VirtioNet{Bridge: "en0", ID: "net0"}
+
+	parsed := roundTrip(t, dev).(*VirtioNet)
+	if parsed.Bridge != dev.Bridge || parsed.ID != dev.ID || parsed.Nat || parsed.Socket != nil {
+		t.Errorf("round-tripped device %+v does not match original %+v", parsed, dev)
+	}
+}
+
+func TestVirtioNetValidateMutualExclusion(t *testing.T) {
+	for _, deviceOpts := range []string{
+		"virtio-net",                // nothing set
+		"virtio-net,nat,bridge=en0", // nat + bridge
+		"virtio-net,bridge=en0,fd=3", // bridge + fd
+	} {
+		if _, err := deviceFromCmdLine(deviceOpts); err == nil {
+			t.Errorf("deviceFromCmdLine(%q) should have returned an error", deviceOpts)
+		}
+	}
+}
+
+func TestVirtioSerialEndpointRoundTrip(t *testing.T) {
+	devs := []VirtioDevice{
+		&VirtioSerial{PTY: true},
+		&VirtioSerial{Stdio: true},
+		&VirtioSerial{TCP: &VirtioSerialTCP{Host: "127.0.0.1", Port: 1234, Listen: true}},
+		&VirtioSerial{TCP: &VirtioSerialTCP{Host: "127.0.0.1", Port: 1234, Listen: false}},
+		&VirtioSerial{Unix: &VirtioSerialUnix{Path: "/tmp/serial.sock", Listen: true}},
+		&VirtioSerial{Unix: &VirtioSerialUnix{Path: "/tmp/serial.sock", Listen: false}},
+	}
+
+	for _, dev := range devs {
+		parsed := roundTrip(t, dev).(*VirtioSerial)
+		switch {
+		case dev.(*VirtioSerial).TCP != nil:
+			if parsed.TCP == nil || *parsed.TCP != *dev.(*VirtioSerial).TCP {
+				t.Errorf("round-tripped device %+v does not match original %+v", parsed, dev)
+			}
+		case dev.(*VirtioSerial).Unix != nil:
+			if parsed.Unix == nil || *parsed.Unix != *dev.(*VirtioSerial).Unix {
+				t.Errorf("round-tripped device %+v does not match original %+v", parsed, dev)
+			}
+		default:
+			if *parsed != *dev.(*VirtioSerial) {
+				t.Errorf("round-tripped device %+v does not match original %+v", parsed, dev)
+			}
+		}
+	}
+}
+
+func TestVirtioSerialFromOptionsRequiresExactlyOneEndpoint(t *testing.T) {
+	for _, deviceOpts := range []string{
+		"virtio-serial",
+		"virtio-serial,pty,stdio",
+		"virtio-serial,logFilePath=/tmp/log,pty",
+	} {
+		if _, err := deviceFromCmdLine(deviceOpts); err == nil {
+			t.Errorf("deviceFromCmdLine(%q) should have returned an error", deviceOpts)
+		}
+	}
+}
+
+// TestVirtioSerialModeOrderIndependent checks that 'mode' is applied to the
+// 'tcp'/'unix' endpoint even when it is given before that endpoint.
+func TestVirtioSerialModeOrderIndependent(t *testing.T) {
+	dev, err := deviceFromCmdLine("virtio-serial,mode=listen,tcp=127.0.0.1:1234")
+	if err != nil {
+		t.Fatalf("deviceFromCmdLine() returned an unexpected error: %v", err)
+	}
+	serial := dev.(*VirtioSerial)
+	if serial.TCP == nil || !serial.TCP.Listen {
+		t.Errorf("expected a listening TCP endpoint, got %+v", serial.TCP)
+	}
+}
+
+func TestVirtioBlkIDRoundTrip(t *testing.T) {
+	dev, err := VirtioBlkNew("/tmp/disk.img")
+	if err != nil {
+		t.Fatalf("VirtioBlkNew() returned an unexpected error: %v", err)
+	}
+	dev.ID = "blk0"
+
+	parsed := roundTrip(t, dev).(*VirtioBlk)
+	if parsed.ID != dev.ID || parsed.ImagePath != dev.ImagePath {
+		t.Errorf("round-tripped device %+v does not match original %+v", parsed, dev)
+	}
+}
+
+func TestUSBMassStorageIDRoundTrip(t *testing.T) {
+	dev, err := USBMassStorageNew("/tmp/disk.iso")
+	if err != nil {
+		t.Fatalf("USBMassStorageNew() returned an unexpected error: %v", err)
+	}
+	dev.(*USBMassStorage).ID = "usb0"
+
+	parsed := roundTrip(t, dev).(*USBMassStorage)
+	if parsed.ID != dev.(*USBMassStorage).ID || parsed.ImagePath != dev.(*USBMassStorage).ImagePath {
+		t.Errorf("round-tripped device %+v does not match original %+v", parsed, dev)
+	}
+}
+
+func TestStorageConfigReadOnlyCacheSyncRoundTrip(t *testing.T) {
+	dev, err := VirtioBlkNew("/tmp/disk.img")
+	if err != nil {
+		t.Fatalf("VirtioBlkNew() returned an unexpected error: %v", err)
+	}
+	dev.ReadOnly = true
+	dev.Cache = CachingUncached
+	dev.Sync = SynchronizationFull
+
+	parsed := roundTrip(t, dev).(*VirtioBlk)
+	if parsed.ReadOnly != dev.ReadOnly || parsed.Cache != dev.Cache || parsed.Sync != dev.Sync {
+		t.Errorf("round-tripped device %+v does not match original %+v", parsed, dev)
+	}
+}
+
+func TestStorageConfigFromOptionsRejectsBadValues(t *testing.T) {
+	for _, deviceOpts := range []string{
+		"virtio-blk,path=/tmp/disk.img,cache=bogus",
+		"virtio-blk,path=/tmp/disk.img,sync=bogus",
+		"virtio-blk,path=/tmp/disk.img,readOnly=notabool",
+	} {
+		if _, err := deviceFromCmdLine(deviceOpts); err == nil {
+			t.Errorf("deviceFromCmdLine(%q) should have returned an error", deviceOpts)
+		}
+	}
+}
+
+func TestVirtioGPURoundTrip(t *testing.T) {
+	dev, err := VirtioGPUNew(1920, 1080)
+	if err != nil {
+		t.Fatalf("VirtioGPUNew() returned an unexpected error: %v", err)
+	}
+	dev.(*VirtioGPU).Scanouts = 2
+
+	parsed := roundTrip(t, dev).(*VirtioGPU)
+	if *parsed != *dev.(*VirtioGPU) {
+		t.Errorf("round-tripped device %+v does not match original %+v", parsed, dev)
+	}
+}
+
+func TestVirtioGPUFromOptionsRejectsZeroDimensions(t *testing.T) {
+	for _, deviceOpts := range []string{
+		"virtio-gpu",
+		"virtio-gpu,width=1920",
+		"virtio-gpu,height=1080",
+		"virtio-gpu,width=0,height=1080",
+	} {
+		if _, err := deviceFromCmdLine(deviceOpts); err == nil {
+			t.Errorf("deviceFromCmdLine(%q) should have returned an error", deviceOpts)
+		}
+	}
+}
+
+func TestVirtioInputRoundTrip(t *testing.T) {
+	for _, inputType := range []VirtioInputType{VirtioInputKeyboard, VirtioInputPointing} {
+		dev, err := VirtioInputNew(inputType)
+		if err != nil {
+			t.Fatalf("VirtioInputNew(%q) returned an unexpected error: %v", inputType, err)
+		}
+
+		parsed := roundTrip(t, dev).(*VirtioInput)
+		if *parsed != *dev.(*VirtioInput) {
+			t.Errorf("round-tripped device %+v does not match original %+v", parsed, dev)
+		}
+	}
+}
+
+func TestVirtioInputFromOptionsRejectsMissingOrUnknownType(t *testing.T) {
+	for _, deviceOpts := range []string{
+		"virtio-input",
+		"virtio-input,type=joystick",
+	} {
+		if _, err := deviceFromCmdLine(deviceOpts); err == nil {
+			t.Errorf("deviceFromCmdLine(%q) should have returned an error", deviceOpts)
+		}
+	}
+}